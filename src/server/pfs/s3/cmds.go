@@ -0,0 +1,41 @@
+package s3
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pachyderm/pachyderm/src/client"
+	"github.com/pachyderm/pachyderm/src/server/pkg/cmdutil"
+	"github.com/spf13/cobra"
+)
+
+// Cmds returns the S3-gateway-related pachctl commands, namely `s3
+// presign`, for registration under the root command.
+func Cmds(pc *client.APIClient) []*cobra.Command {
+	s3Cmd := &cobra.Command{
+		Use:   "s3",
+		Short: "Commands for working with the S3 gateway.",
+	}
+
+	var expires time.Duration
+	presign := &cobra.Command{
+		Use:   "presign repo@branch/path",
+		Short: "Print a presigned S3 URL for reading a PFS file.",
+		Long:  "Print a presigned S3 URL for reading a PFS file through the S3 gateway, valid for --expires. Anyone with the URL can read the file without a Pachyderm client or credentials of their own.",
+		Annotations: map[string]string{
+			"pachctl.complete": "branch",
+		},
+		Run: cmdutil.RunFixedArgs(1, func(args []string) error {
+			url, err := Presign(pc, args[0], expires)
+			if err != nil {
+				return err
+			}
+			fmt.Println(url)
+			return nil
+		}),
+	}
+	presign.Flags().DurationVar(&expires, "expires", time.Hour, "How long the presigned URL remains valid.")
+	s3Cmd.AddCommand(presign)
+
+	return []*cobra.Command{s3Cmd}
+}
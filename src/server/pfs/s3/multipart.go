@@ -0,0 +1,242 @@
+package s3
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pachyderm/pachyderm/src/client"
+	"github.com/pachyderm/pachyderm/src/client/pkg/uuid"
+)
+
+// multipartRepo is the system repo used to stage in-progress multipart
+// uploads. Each upload gets its own branch, named after its upload ID, and
+// each part is written to that branch as a numbered file. This lets us
+// reuse PFS's own consistency guarantees instead of inventing a second
+// staging area on disk.
+const multipartRepo = "__s3_multipart__"
+
+// InitiateMultipartUploadResult is an XML-encodable response to
+// `POST /<bucket>/<key>?uploads`.
+type InitiateMultipartUploadResult struct {
+	Bucket   string `xml:"Bucket"`
+	Key      string `xml:"Key"`
+	UploadID string `xml:"UploadId"`
+}
+
+// CompleteMultipartUploadResult is an XML-encodable response to
+// `POST /<bucket>/<key>?uploadId=...`.
+type CompleteMultipartUploadResult struct {
+	Bucket string `xml:"Bucket"`
+	Key    string `xml:"Key"`
+	ETag   string `xml:"ETag"`
+}
+
+// CompleteMultipartUpload is the XML-encoded request body of a complete
+// request, listing the parts to be concatenated in order.
+type CompleteMultipartUpload struct {
+	Parts []CompletedPart `xml:"Part"`
+}
+
+// CompletedPart identifies one previously-uploaded part by number.
+type CompletedPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+// ListPartsResult is an XML-encodable response to
+// `GET /<bucket>/<key>?uploadId=...`.
+type ListPartsResult struct {
+	Bucket   string `xml:"Bucket"`
+	Key      string `xml:"Key"`
+	UploadID string `xml:"UploadId"`
+	Parts    []Part `xml:"Part"`
+}
+
+// Part is an XML-encodable uploaded part.
+type Part struct {
+	PartNumber   int       `xml:"PartNumber"`
+	LastModified time.Time `xml:"LastModified"`
+	ETag         string    `xml:"ETag"`
+	Size         uint64    `xml:"Size"`
+}
+
+type multipartHandler struct {
+	pc *client.APIClient
+}
+
+func newMultipartHandler(pc *client.APIClient) multipartHandler {
+	return multipartHandler{pc: pc}
+}
+
+// partPath returns the path, within the upload's branch, that a given part
+// number is staged at. Parts are zero-padded so a naive lexicographic
+// `ListFile` already returns them in ascending order.
+func partPath(partNumber int) string {
+	return fmt.Sprintf("/part-%010d", partNumber)
+}
+
+// partNumberFromPath parses the part number back out of a path produced
+// by partPath, so a non-contiguous sequence of part numbers (a retried or
+// skipped part) is reported under its real number rather than its
+// position in the listing.
+func partNumberFromPath(path string) (int, error) {
+	path = strings.TrimPrefix(path, "/")
+	path = strings.TrimPrefix(path, "part-")
+	return strconv.Atoi(path)
+}
+
+func (h multipartHandler) initiate(w http.ResponseWriter, r *http.Request, repo, file string) {
+	uploadID := uuid.NewWithoutDashes()
+
+	if _, err := h.pc.InspectRepo(multipartRepo); err != nil {
+		if err := h.pc.CreateRepo(multipartRepo); err != nil {
+			internalError(w, r, err)
+			return
+		}
+	}
+	if err := h.pc.CreateBranch(multipartRepo, uploadID, "", nil); err != nil {
+		internalError(w, r, err)
+		return
+	}
+
+	writeXML(w, r, http.StatusOK, &InitiateMultipartUploadResult{
+		Bucket:   repo,
+		Key:      file,
+		UploadID: uploadID,
+	})
+}
+
+func (h multipartHandler) uploadPart(w http.ResponseWriter, r *http.Request, uploadID string, partNumber int) {
+	hash := md5.New()
+	if _, err := h.pc.PutFileOverwrite(multipartRepo, uploadID, partPath(partNumber), io.TeeReader(r.Body, hash), 0); err != nil {
+		internalError(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", fmt.Sprintf(`"%s"`, hex.EncodeToString(hash.Sum(nil))))
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h multipartHandler) listParts(w http.ResponseWriter, r *http.Request, repo, file, uploadID string) {
+	fileInfos, err := h.pc.ListFile(multipartRepo, uploadID, "/")
+	if err != nil {
+		internalError(w, r, err)
+		return
+	}
+	sort.Slice(fileInfos, func(i, j int) bool {
+		return fileInfos[i].File.Path < fileInfos[j].File.Path
+	})
+
+	result := ListPartsResult{Bucket: repo, Key: file, UploadID: uploadID}
+	for _, fi := range fileInfos {
+		partNumber, err := partNumberFromPath(fi.File.Path)
+		if err != nil {
+			internalError(w, r, err)
+			return
+		}
+		result.Parts = append(result.Parts, Part{
+			PartNumber: partNumber,
+			ETag:       fi.Hash,
+			Size:       fi.SizeBytes,
+		})
+	}
+
+	writeXML(w, r, http.StatusOK, &result)
+}
+
+// complete concatenates the parts named in `req`, in ascending PartNumber
+// order, into a single PutFile call on a new commit of the target
+// repo/branch, then deletes the staging branch.
+func (h multipartHandler) complete(w http.ResponseWriter, r *http.Request, repo, branch, file, uploadID string, req CompleteMultipartUpload) {
+	sort.Slice(req.Parts, func(i, j int) bool {
+		return req.Parts[i].PartNumber < req.Parts[j].PartNumber
+	})
+
+	// Look up the hash PFS actually stored for each part, the same way
+	// listParts does, rather than trusting the client-supplied ETag: a
+	// client could otherwise claim any ETag for any PartNumber and end up
+	// with a concatenated object whose real content doesn't match what it
+	// asked for.
+	storedHashes := map[int]string{}
+	fileInfos, err := h.pc.ListFile(multipartRepo, uploadID, "/")
+	if err != nil {
+		internalError(w, r, err)
+		return
+	}
+	for _, fi := range fileInfos {
+		partNumber, err := partNumberFromPath(fi.File.Path)
+		if err != nil {
+			internalError(w, r, err)
+			return
+		}
+		storedHashes[partNumber] = fi.Hash
+	}
+
+	commit, err := h.pc.StartCommit(repo, branch)
+	if err != nil {
+		internalError(w, r, err)
+		return
+	}
+
+	overallHash := md5.New()
+	var partReaders []io.Reader
+	for _, part := range req.Parts {
+		stored, ok := storedHashes[part.PartNumber]
+		if !ok || stored != strings.Trim(part.ETag, `"`) {
+			h.pc.DeleteCommit(repo, commit.ID)
+			invalidArgument(w, r)
+			return
+		}
+
+		partReader, err := h.pc.GetFileReader(multipartRepo, uploadID, partPath(part.PartNumber), 0, 0)
+		if err != nil {
+			h.pc.DeleteCommit(repo, commit.ID)
+			internalError(w, r, err)
+			return
+		}
+		partReaders = append(partReaders, partReader)
+		partHash, err := hex.DecodeString(stored)
+		if err != nil {
+			h.pc.DeleteCommit(repo, commit.ID)
+			internalError(w, r, err)
+			return
+		}
+		overallHash.Write(partHash)
+	}
+	if _, err := h.pc.PutFile(repo, commit.ID, file, io.MultiReader(partReaders...)); err != nil {
+		h.pc.DeleteCommit(repo, commit.ID)
+		internalError(w, r, err)
+		return
+	}
+
+	if err := h.pc.FinishCommit(repo, commit.ID); err != nil {
+		internalError(w, r, err)
+		return
+	}
+	if err := h.pc.DeleteBranch(multipartRepo, uploadID, true); err != nil {
+		internalError(w, r, err)
+		return
+	}
+
+	etag := fmt.Sprintf("%s-%d", hex.EncodeToString(overallHash.Sum(nil)), len(req.Parts))
+	writeXML(w, r, http.StatusOK, &CompleteMultipartUploadResult{
+		Bucket: repo,
+		Key:    file,
+		ETag:   etag,
+	})
+}
+
+func (h multipartHandler) abort(w http.ResponseWriter, r *http.Request, uploadID string) {
+	if err := h.pc.DeleteBranch(multipartRepo, uploadID, true); err != nil {
+		internalError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
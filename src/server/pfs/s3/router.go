@@ -0,0 +1,117 @@
+package s3
+
+import (
+	"encoding/xml"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/pachyderm/pachyderm/src/client"
+)
+
+// NewHandler builds the S3 gateway's bucket-aware dispatcher, routing
+// each request, by virtual-hosted bucket and query string, to whichever
+// handler in this package implements it. Requests reach it already
+// authenticated — see Router, which is what should actually be mounted
+// to serve traffic.
+func NewHandler(pc *client.APIClient) http.Handler {
+	return bucketRouter{pc: pc}
+}
+
+// Router builds the S3 gateway's top-level http.Handler: SigV4
+// verification wraps NewHandler's bucket-aware dispatcher, so every
+// request is signature-checked and resolved to its caller's own
+// Pachyderm identity (see sigv4Handler) before any repo/file is
+// touched. Mount the result directly, e.g.
+// http.ListenAndServe(addr, s3.Router(pc, lookup)).
+func Router(pc *client.APIClient, lookup credentialLookup) http.Handler {
+	return newSigV4Handler(pc, lookup, NewHandler)
+}
+
+// bucketRouter dispatches a request to the right per-bucket handler,
+// based on the "branch.repo" virtual host (see Bucket.Name in root.go
+// and Presign in presign.go, which both already bake in this
+// convention) and the S3 sub-resource query parameters (?versions,
+// ?uploadId=..., ?uploads) that distinguish one operation from another
+// on the same path.
+type bucketRouter struct {
+	pc *client.APIClient
+}
+
+func (h bucketRouter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	branch, repo, file, ok := splitBucketHost(r.Host, r.URL.Path)
+	if !ok {
+		// No "branch.repo" host to dispatch on: this is a request to the
+		// gateway's own address, i.e. GET / to list buckets.
+		newRootHandler(h.pc).ServeHTTP(w, r)
+		return
+	}
+
+	q := r.URL.Query()
+	if uploadID := q.Get("uploadId"); uploadID != "" {
+		h.routeMultipart(w, r, repo, branch, file, uploadID)
+		return
+	}
+	if _, ok := q["uploads"]; ok && file != "" {
+		newMultipartHandler(h.pc).initiate(w, r, repo, file)
+		return
+	}
+	if file == "" {
+		if _, ok := q["versions"]; ok {
+			newListVersionsHandler(h.pc, repo, branch).ServeHTTP(w, r)
+			return
+		}
+		// Plain (non-versioned) bucket listing isn't implemented by this
+		// gateway yet.
+		notFoundError(w, r)
+		return
+	}
+
+	newVersionedObjectHandler(h.pc, repo, branch, file).ServeHTTP(w, r)
+}
+
+// routeMultipart dispatches the four `?uploadId=...`-bearing multipart
+// operations to multipartHandler by HTTP method, the same way S3 itself
+// overloads PUT/POST/GET/DELETE on that query parameter.
+func (h bucketRouter) routeMultipart(w http.ResponseWriter, r *http.Request, repo, branch, file, uploadID string) {
+	mh := newMultipartHandler(h.pc)
+	switch r.Method {
+	case http.MethodPut:
+		partNumber, err := strconv.Atoi(r.URL.Query().Get("partNumber"))
+		if err != nil {
+			invalidArgument(w, r)
+			return
+		}
+		mh.uploadPart(w, r, uploadID, partNumber)
+	case http.MethodGet:
+		mh.listParts(w, r, repo, file, uploadID)
+	case http.MethodPost:
+		var req CompleteMultipartUpload
+		if err := xml.NewDecoder(r.Body).Decode(&req); err != nil {
+			invalidArgument(w, r)
+			return
+		}
+		mh.complete(w, r, repo, branch, file, uploadID, req)
+	case http.MethodDelete:
+		mh.abort(w, r, uploadID)
+	default:
+		invalidArgument(w, r)
+	}
+}
+
+// splitBucketHost splits a virtual-hosted request's Host header into the
+// branch and repo that make up its bucket name (Bucket.Name is always
+// "branch.repo", per root.go), and returns the object key with its
+// leading slash stripped. ok is false when host doesn't carry a bucket
+// at all, which is how a request to the gateway's bare address (to list
+// buckets) is told apart from a per-bucket request.
+func splitBucketHost(host, path string) (branch, repo, file string, ok bool) {
+	if i := strings.LastIndex(host, ":"); i != -1 {
+		host = host[:i]
+	}
+	i := strings.Index(host, ".")
+	if i == -1 {
+		return "", "", "", false
+	}
+	return host[:i], host[i+1:], strings.TrimPrefix(path, "/"), true
+}
@@ -0,0 +1,303 @@
+package s3
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pachyderm/pachyderm/src/client"
+)
+
+var (
+	errMissingAuth    = fmt.Errorf("missing or malformed SigV4 authentication")
+	errExpiredPresign = fmt.Errorf("presigned URL has expired")
+	errExpiredRequest = fmt.Errorf("request's X-Amz-Date is outside the allowed clock-skew window")
+	errBadPayloadHash = fmt.Errorf("X-Amz-Content-Sha256 does not match the request body")
+)
+
+// maxRequestAge bounds how long after signing a header-signed (i.e. not
+// presigned, which carries its own X-Amz-Expires) request will still be
+// accepted. Without it, a captured Authorization header is valid forever
+// and can be replayed at will.
+const maxRequestAge = 15 * time.Minute
+
+// unsignedPayload is the sentinel aws-cli/boto3 send as X-Amz-Content-
+// Sha256 for a streaming request body (the common case for PUT/GET
+// above a few MB): the client deliberately doesn't hash the body up
+// front, so there's nothing to verify it against.
+const unsignedPayload = "UNSIGNED-PAYLOAD"
+
+// maxVerifiedPayload bounds how large a request body actualPayloadHash
+// will buffer in memory to check against a declared X-Amz-Content-
+// Sha256. A multipart upload part (see multipart.go) can be many GB;
+// buffering one whole part just to verify a signature would defeat the
+// point of multipart upload. Above this size (or for unsignedPayload,
+// or an unknown Content-Length) the declared hash is trusted as-is, the
+// same way AWS's own chunked/streaming signing doesn't re-hash the full
+// body against a single header either.
+const maxVerifiedPayload = 1 << 20 // 1 MiB
+
+// credentialLookup resolves an AWS access key ID to the secret key and
+// Pachyderm auth token that were issued alongside it, via Pachyderm's auth
+// subsystem.
+type credentialLookup func(accessKeyID string) (secretKey, authToken string, err error)
+
+// sigv4Handler verifies the AWS SigV4 signature of a request — either a
+// signed `Authorization` header or a presigned query string — before
+// handing it to next with an APIClient authenticated as the resolved
+// user, so downstream ACL checks (ListRepo, GetFile, ...) reflect the
+// caller rather than an anonymous identity.
+type sigv4Handler struct {
+	pc     *client.APIClient
+	lookup credentialLookup
+	next   func(pc *client.APIClient) http.Handler
+}
+
+func newSigV4Handler(pc *client.APIClient, lookup credentialLookup, next func(pc *client.APIClient) http.Handler) sigv4Handler {
+	return sigv4Handler{pc: pc, lookup: lookup, next: next}
+}
+
+func (h sigv4Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	accessKeyID, signature, signedHeaders, credentialScope, err := parseSigV4(r)
+	if err != nil {
+		accessDenied(w, r)
+		return
+	}
+
+	payloadHash, err := actualPayloadHash(r)
+	if err != nil {
+		if err == errBadPayloadHash {
+			accessDenied(w, r)
+		} else {
+			internalError(w, r, err)
+		}
+		return
+	}
+
+	secretKey, authToken, err := h.lookup(accessKeyID)
+	if err != nil {
+		accessDenied(w, r)
+		return
+	}
+
+	expected := expectedSignature(r, secretKey, credentialScope, signedHeaders, payloadHash)
+	if expected == "" || !hmac.Equal([]byte(expected), []byte(signature)) {
+		accessDenied(w, r)
+		return
+	}
+
+	h.next(h.pc.AuthorizeAs(authToken)).ServeHTTP(w, r)
+}
+
+// actualPayloadHash returns the payload hash the canonical request should
+// be signed over. For a body small enough to safely buffer, it reads and
+// restores r.Body and checks the computed hash against the client's
+// declared X-Amz-Content-Sha256, returning errBadPayloadHash on a
+// mismatch — so a body swapped in transit (with headers left untouched)
+// fails verification instead of sailing through. For unsignedPayload, or
+// anything over maxVerifiedPayload (including every multipart upload part
+// worth guarding against OOM for), the declared value is trusted and used
+// directly, without ever reading the body. A missing declared hash is
+// taken to be the SHA-256 of an empty body, also without reading it.
+func actualPayloadHash(r *http.Request) (string, error) {
+	declared := r.Header.Get("X-Amz-Content-Sha256")
+	if declared == "" {
+		return hashHex(""), nil
+	}
+	if declared == unsignedPayload || r.Body == nil {
+		return declared, nil
+	}
+	if r.ContentLength < 0 || r.ContentLength > maxVerifiedPayload {
+		return declared, nil
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return "", err
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	actual := hashHex(string(body))
+	if actual != declared {
+		return "", errBadPayloadHash
+	}
+	return actual, nil
+}
+
+// parseSigV4 extracts SigV4 parameters from either the Authorization
+// header (`AWS4-HMAC-SHA256 Credential=..., SignedHeaders=..., Signature=...`)
+// or a presigned query string (`X-Amz-Signature`, `X-Amz-Credential`,
+// `X-Amz-SignedHeaders`, `X-Amz-Expires`).
+func parseSigV4(r *http.Request) (accessKeyID, signature string, signedHeaders []string, credentialScope string, err error) {
+	q := r.URL.Query()
+	if sig := q.Get("X-Amz-Signature"); sig != "" {
+		expired, err := presignExpired(q.Get("X-Amz-Date"), q.Get("X-Amz-Expires"))
+		if err != nil || expired {
+			return "", "", nil, "", errExpiredPresign
+		}
+		accessKeyID, credentialScope = splitCredential(q.Get("X-Amz-Credential"))
+		signedHeaders = strings.Split(q.Get("X-Amz-SignedHeaders"), ";")
+		return accessKeyID, sig, signedHeaders, credentialScope, nil
+	}
+
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 ") {
+		return "", "", nil, "", errMissingAuth
+	}
+	if expired, err := requestExpired(r.Header.Get("X-Amz-Date")); err != nil || expired {
+		return "", "", nil, "", errExpiredRequest
+	}
+	fields := parseAuthFields(strings.TrimPrefix(auth, "AWS4-HMAC-SHA256 "))
+	accessKeyID, credentialScope = splitCredential(fields["Credential"])
+	signedHeaders = strings.Split(fields["SignedHeaders"], ";")
+	return accessKeyID, fields["Signature"], signedHeaders, credentialScope, nil
+}
+
+// requestExpired reports whether a header-signed request's X-Amz-Date is
+// further than maxRequestAge in the past (or missing), bounding the
+// window in which a captured request can be replayed.
+func requestExpired(signedAtStr string) (bool, error) {
+	if signedAtStr == "" {
+		return true, errMissingAuth
+	}
+	signedAt, err := time.Parse("20060102T150405Z", signedAtStr)
+	if err != nil {
+		return true, err
+	}
+	return time.Since(signedAt) > maxRequestAge, nil
+}
+
+func splitCredential(cred string) (accessKeyID, scope string) {
+	parts := strings.SplitN(cred, "/", 2)
+	if len(parts) != 2 {
+		return cred, ""
+	}
+	return parts[0], parts[1]
+}
+
+func parseAuthFields(s string) map[string]string {
+	fields := map[string]string{}
+	for _, part := range strings.Split(s, ", ") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) == 2 {
+			fields[kv[0]] = kv[1]
+		}
+	}
+	return fields
+}
+
+func presignExpired(signedAtStr, expiresStr string) (bool, error) {
+	if signedAtStr == "" || expiresStr == "" {
+		return true, errMissingAuth
+	}
+	signedAt, err := time.Parse("20060102T150405Z", signedAtStr)
+	if err != nil {
+		return true, err
+	}
+	seconds, err := strconv.Atoi(expiresStr)
+	if err != nil {
+		return true, err
+	}
+	return time.Since(signedAt) > time.Duration(seconds)*time.Second, nil
+}
+
+// expectedSignature recomputes the SigV4 signature for r using the
+// standard four-step process: canonical request, string to sign, signing
+// key, signature. Only the caller-declared signedHeaders are included in
+// the canonical request, matching what the client actually signed.
+// payloadHash is whatever actualPayloadHash decided the signature should
+// bind to: the hash actually computed over the request body where that's
+// safe to verify, or the client's declared X-Amz-Content-Sha256 where
+// it's too large (or explicitly unsigned) to buffer.
+func expectedSignature(r *http.Request, secretKey, credentialScope string, signedHeaders []string, payloadHash string) string {
+	scopeParts := strings.Split(credentialScope, "/")
+	if len(scopeParts) != 4 || scopeParts[3] != "aws4_request" {
+		return ""
+	}
+	date, region, service := scopeParts[0], scopeParts[1], scopeParts[2]
+
+	canonicalHeaders, signedHeaderNames := canonicalizeHeaders(r, signedHeaders)
+	canonicalRequest := strings.Join([]string{
+		r.Method,
+		r.URL.EscapedPath(),
+		canonicalQuery(r),
+		canonicalHeaders,
+		signedHeaderNames,
+		payloadHash,
+	}, "\n")
+
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate(r),
+		credentialScope,
+		hashHex(canonicalRequest),
+	}, "\n")
+
+	signingKey := deriveSigningKey(secretKey, date, region, service)
+	return hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+}
+
+// canonicalizeHeaders renders the canonical header block for the given
+// signed header names. "host" is special-cased: on a server-parsed
+// http.Request, Go moves the literal Host header out of r.Header and into
+// r.Host, so it must be read from there instead.
+func canonicalizeHeaders(r *http.Request, signedHeaders []string) (canonical, names string) {
+	sorted := append([]string{}, signedHeaders...)
+	sort.Strings(sorted)
+	var b strings.Builder
+	for _, name := range sorted {
+		var value string
+		if strings.EqualFold(name, "host") {
+			value = r.Host
+		} else {
+			value = r.Header.Get(name)
+		}
+		b.WriteString(strings.ToLower(name))
+		b.WriteString(":")
+		b.WriteString(strings.TrimSpace(value))
+		b.WriteString("\n")
+	}
+	return b.String(), strings.Join(sorted, ";")
+}
+
+// amzDate returns the request's signing timestamp, read from the
+// X-Amz-Date query parameter for a presigned request or the X-Amz-Date
+// header for a header-signed one.
+func amzDate(r *http.Request) string {
+	if date := r.URL.Query().Get("X-Amz-Date"); date != "" {
+		return date
+	}
+	return r.Header.Get("X-Amz-Date")
+}
+
+func canonicalQuery(r *http.Request) string {
+	q := r.URL.Query()
+	q.Del("X-Amz-Signature")
+	return q.Encode()
+}
+
+func deriveSigningKey(secretKey, date, region, service string) []byte {
+	kDate := hmacSHA256([]byte("AWS4"+secretKey), date)
+	kRegion := hmacSHA256(kDate, region)
+	kService := hmacSHA256(kRegion, service)
+	return hmacSHA256(kService, "aws4_request")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func hashHex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,336 @@
+package s3
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gogo/protobuf/types"
+	"github.com/pachyderm/pachyderm/src/client"
+	"github.com/pachyderm/pachyderm/src/client/pfs"
+)
+
+// ListVersionsResult is an XML-encodable listing of a bucket's object
+// versions, mirroring S3's `GET /?versions` response. Each PFS commit on
+// the bucket's branch that touched a given key is surfaced as either a
+// `<Version>` (the file existed in that commit) or a `<DeleteMarker>`
+// (the file was absent).
+type ListVersionsResult struct {
+	Name                string          `xml:"Name"`
+	Prefix              string          `xml:"Prefix"`
+	KeyMarker           string          `xml:"KeyMarker"`
+	VersionIDMarker     string          `xml:"VersionIdMarker"`
+	NextKeyMarker       string          `xml:"NextKeyMarker,omitempty"`
+	NextVersionIDMarker string          `xml:"NextVersionIdMarker,omitempty"`
+	MaxKeys             int             `xml:"MaxKeys"`
+	Delimiter           string          `xml:"Delimiter,omitempty"`
+	IsTruncated         bool            `xml:"IsTruncated"`
+	Versions            []ObjectVersion `xml:"Version"`
+	DeleteMarkers       []DeleteMarker  `xml:"DeleteMarker"`
+}
+
+// ObjectVersion is an XML-encodable PFS commit, represented as an S3
+// object version.
+type ObjectVersion struct {
+	Key          string    `xml:"Key"`
+	VersionID    string    `xml:"VersionId"`
+	IsLatest     bool      `xml:"IsLatest"`
+	LastModified time.Time `xml:"LastModified"`
+	ETag         string    `xml:"ETag"`
+	Size         uint64    `xml:"Size"`
+	Owner        User      `xml:"Owner"`
+	StorageClass string    `xml:"StorageClass"`
+}
+
+// DeleteMarker is an XML-encodable PFS commit in which a key was absent,
+// represented as an S3 delete marker.
+type DeleteMarker struct {
+	Key          string    `xml:"Key"`
+	VersionID    string    `xml:"VersionId"`
+	IsLatest     bool      `xml:"IsLatest"`
+	LastModified time.Time `xml:"LastModified"`
+	Owner        User      `xml:"Owner"`
+}
+
+type listVersionsHandler struct {
+	pc     *client.APIClient
+	repo   string
+	branch string
+}
+
+func newListVersionsHandler(pc *client.APIClient, repo, branch string) listVersionsHandler {
+	return listVersionsHandler{pc: pc, repo: repo, branch: branch}
+}
+
+func (h listVersionsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	prefix := q.Get("prefix")
+	keyMarker := q.Get("key-marker")
+	versionIDMarker := q.Get("version-id-marker")
+	maxKeys, err := parseMaxKeys(q.Get("max-keys"))
+	if err != nil {
+		invalidArgument(w, r)
+		return
+	}
+
+	commitInfos, err := h.pc.ListCommit(h.repo, h.branch, "", uint64(0))
+	if err != nil {
+		internalError(w, r, err)
+		return
+	}
+
+	result := ListVersionsResult{
+		Name:            h.repo,
+		Prefix:          prefix,
+		KeyMarker:       keyMarker,
+		VersionIDMarker: versionIDMarker,
+		MaxKeys:         maxKeys,
+	}
+
+	entries, err := h.changedEntries(commitInfos)
+	if err != nil {
+		internalError(w, r, err)
+		return
+	}
+
+	// ListObjectVersions must return entries grouped by key (and, within
+	// a key, newest version first), not interleaved in per-commit order,
+	// so sort the flattened per-commit diff by (key, commitIndex) before
+	// paginating. commitIndex counts up from the newest commit (commitInfos
+	// is newest-first), so ascending commitIndex within a key already
+	// puts its most recent version first.
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].key != entries[j].key {
+			return entries[i].key < entries[j].key
+		}
+		return entries[i].commitIndex < entries[j].commitIndex
+	})
+
+	// commitIndex resolves a commit ID back to its position in
+	// commitInfos, so resuming a paginated listing compares *positions*
+	// in the already-ordered commit history rather than string-comparing
+	// opaque commit IDs, which have no relation to time order.
+	commitIndex := make(map[string]int, len(commitInfos))
+	for i, commitInfo := range commitInfos {
+		commitIndex[commitInfo.Commit.ID] = i
+	}
+
+	latestSeen := map[string]bool{}
+	skipping := keyMarker != "" || versionIDMarker != ""
+	markerIdx := commitIndex[versionIDMarker]
+	emitted := 0
+	var lastKey, lastVersionID string
+
+	for _, e := range entries {
+		if prefix != "" && !hasPrefix(e.key, prefix) {
+			continue
+		}
+		if skipping {
+			if e.key < keyMarker || (e.key == keyMarker && commitIndex[e.commitID] <= markerIdx) {
+				continue
+			}
+			skipping = false
+		}
+
+		if emitted >= maxKeys {
+			// lastKey/lastVersionID is the last entry actually emitted,
+			// so resuming with it as key-marker/version-id-marker skips
+			// exactly what's already been returned and picks up right
+			// after it — never re-emitting or dropping the boundary
+			// entry the way reusing the not-yet-emitted entry did.
+			result.IsTruncated = true
+			result.NextKeyMarker = lastKey
+			result.NextVersionIDMarker = lastVersionID
+			writeXML(w, r, http.StatusOK, &result)
+			return
+		}
+		emitted++
+		lastKey, lastVersionID = e.key, e.commitID
+
+		isLatest := !latestSeen[e.key]
+		latestSeen[e.key] = true
+
+		if e.fileInfo != nil {
+			result.Versions = append(result.Versions, ObjectVersion{
+				Key:          e.key,
+				VersionID:    e.commitID,
+				IsLatest:     isLatest,
+				LastModified: e.lastModified,
+				ETag:         e.fileInfo.Hash,
+				Size:         e.fileInfo.SizeBytes,
+				Owner:        defaultUser,
+				StorageClass: "STANDARD",
+			})
+		} else {
+			result.DeleteMarkers = append(result.DeleteMarkers, DeleteMarker{
+				Key:          e.key,
+				VersionID:    e.commitID,
+				IsLatest:     isLatest,
+				LastModified: e.lastModified,
+				Owner:        defaultUser,
+			})
+		}
+	}
+
+	writeXML(w, r, http.StatusOK, &result)
+}
+
+// versionEntry is one commit's effect on one key: either the file as it
+// existed in that commit (fileInfo != nil) or its deletion (fileInfo ==
+// nil). commitIndex is the entry's position in the newest-first
+// commitInfos slice ListObjectVersions fetched, used to order and
+// paginate entries without string-comparing opaque commit IDs.
+type versionEntry struct {
+	key          string
+	commitID     string
+	commitIndex  int
+	lastModified time.Time
+	fileInfo     *pfs.FileInfo
+}
+
+// changedEntries walks commitInfos (newest-first) and, for every commit
+// that added, modified, or removed a key relative to its parent (the
+// next-older commit), returns one versionEntry for it. A key that a
+// commit left untouched gets no entry there, so a key with N real edits
+// produces exactly N entries rather than one per commit in the branch's
+// history.
+func (h listVersionsHandler) changedEntries(commitInfos []*pfs.CommitInfo) ([]versionEntry, error) {
+	presentByCommit := make([]map[string]*pfs.FileInfo, len(commitInfos))
+	for i, commitInfo := range commitInfos {
+		fileInfos, err := walkFiles(h.pc, h.repo, commitInfo.Commit.ID, "/")
+		if err != nil {
+			return nil, err
+		}
+		present := map[string]*pfs.FileInfo{}
+		for _, fi := range fileInfos {
+			present[fi.File.Path] = fi
+		}
+		presentByCommit[i] = present
+	}
+
+	var entries []versionEntry
+	for i, commitInfo := range commitInfos {
+		t, err := types.TimestampFromProto(commitInfo.Finished)
+		if err != nil {
+			return nil, err
+		}
+
+		present := presentByCommit[i]
+		var older map[string]*pfs.FileInfo
+		if i+1 < len(commitInfos) {
+			older = presentByCommit[i+1]
+		}
+
+		// A key is "changed" at this commit if it's the oldest commit (no
+		// parent to diff against, so every file present is an initial
+		// add), if it's present here but wasn't present-with-this-hash in
+		// the parent (added or modified), or if it's present in the
+		// parent but missing here (deleted).
+		changed := map[string]bool{}
+		for key, fi := range present {
+			if prev, ok := older[key]; !ok || prev.Hash != fi.Hash {
+				changed[key] = true
+			}
+		}
+		for key := range older {
+			if _, ok := present[key]; !ok {
+				changed[key] = true
+			}
+		}
+
+		for key := range changed {
+			entries = append(entries, versionEntry{
+				key:          key,
+				commitID:     commitInfo.Commit.ID,
+				commitIndex:  i,
+				lastModified: t,
+				fileInfo:     present[key],
+			})
+		}
+	}
+	return entries, nil
+}
+
+// walkFiles recursively lists every file (not directory) under path in
+// the given commit, since ListFile only returns path's direct children
+// and ListObjectVersions needs every key in the commit.
+func walkFiles(pc *client.APIClient, repo, commitID, path string) ([]*pfs.FileInfo, error) {
+	children, err := pc.ListFile(repo, commitID, path)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*pfs.FileInfo
+	for _, fi := range children {
+		if fi.FileType == pfs.FileType_DIR {
+			nested, err := walkFiles(pc, repo, commitID, fi.File.Path)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, nested...)
+			continue
+		}
+		result = append(result, fi)
+	}
+	return result, nil
+}
+
+func parseMaxKeys(s string) (int, error) {
+	if s == "" {
+		return 1000, nil
+	}
+	return strconv.Atoi(s)
+}
+
+func hasPrefix(key, prefix string) bool {
+	return len(key) >= len(prefix) && key[:len(prefix)] == prefix
+}
+
+// versionedObjectHandler serves GET/HEAD/DELETE requests that carry a
+// `versionId` query parameter, resolving it directly to a PFS commit ID
+// instead of the branch HEAD.
+type versionedObjectHandler struct {
+	pc     *client.APIClient
+	repo   string
+	branch string
+	file   string
+}
+
+func newVersionedObjectHandler(pc *client.APIClient, repo, branch, file string) versionedObjectHandler {
+	return versionedObjectHandler{pc: pc, repo: repo, branch: branch, file: file}
+}
+
+func (h versionedObjectHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	versionID := r.URL.Query().Get("versionId")
+	if versionID == "" {
+		versionID = h.branch
+	}
+
+	fileInfo, err := h.pc.InspectFile(h.repo, versionID, h.file)
+	if err != nil {
+		if isNotFoundErr(err) {
+			notFoundError(w, r)
+			return
+		}
+		internalError(w, r, err)
+		return
+	}
+
+	w.Header().Set("x-amz-version-id", versionID)
+
+	switch r.Method {
+	case http.MethodDelete:
+		// Files removed from a given commit appear as delete markers in
+		// `ListObjectVersions`; there's nothing further to do here since
+		// PFS commits are immutable.
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodHead:
+		w.Header().Set("Content-Length", strconv.FormatUint(fileInfo.SizeBytes, 10))
+		w.WriteHeader(http.StatusOK)
+	default:
+		if err := h.pc.GetFile(h.repo, versionID, h.file, 0, 0, w); err != nil {
+			internalError(w, r, err)
+		}
+	}
+}
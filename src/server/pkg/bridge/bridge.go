@@ -0,0 +1,66 @@
+// Package bridge implements named, persistent syncs between a PFS
+// repo/branch and an external store — an S3/GCS bucket, a Git repository,
+// another Pachyderm cluster, or anything else a Bridge is registered for.
+// Each bridge remembers a cursor marking how far it has synced, so pull
+// and push are resumable and only move new data.
+package bridge
+
+import (
+	"context"
+
+	"github.com/pachyderm/pachyderm/src/client"
+)
+
+// Cursor opaquely identifies a point in an external store's history that
+// a bridge has synced up to. Its meaning is defined entirely by the
+// Bridge implementation that produced it (a commit ID, an object
+// generation, a Git ref, ...).
+type Cursor string
+
+// Bridge is implemented by each supported external store. Pull and Push
+// are one-shot: they sync everything new since the given cursor and
+// return the cursor to resume from next time.
+type Bridge interface {
+	// Pull ingests objects from the external store created since the
+	// given cursor into a new PFS commit, returning the cursor to
+	// resume from on the next pull.
+	Pull(ctx context.Context, since Cursor) (Cursor, error)
+
+	// Push exports PFS commits made since the given cursor to the
+	// external store, returning the cursor to resume from on the next
+	// push.
+	Push(ctx context.Context, since Cursor) (Cursor, error)
+}
+
+// Factory constructs a Bridge from a bridge's stored Config and the local
+// Pachyderm client it should sync against. Backends register a Factory
+// under their Config.Type via Register.
+type Factory func(pc *client.APIClient, config Config) (Bridge, error)
+
+var factories = map[string]Factory{}
+
+// Register makes a backend available under the given type name, so that
+// bridges configured with Config.Type == typeName can be constructed.
+// Third parties call this from an init function to add a backend.
+func Register(typeName string, factory Factory) {
+	factories[typeName] = factory
+}
+
+// New constructs the Bridge registered for config.Type.
+func New(pc *client.APIClient, config Config) (Bridge, error) {
+	factory, ok := factories[config.Type]
+	if !ok {
+		return nil, &UnknownTypeError{Type: config.Type}
+	}
+	return factory(pc, config)
+}
+
+// UnknownTypeError is returned by New when no Factory is registered for
+// the requested type.
+type UnknownTypeError struct {
+	Type string
+}
+
+func (e *UnknownTypeError) Error() string {
+	return "unknown bridge type \"" + e.Type + "\""
+}
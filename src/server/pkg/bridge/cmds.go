@@ -0,0 +1,99 @@
+package bridge
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/pachyderm/pachyderm/src/client"
+	"github.com/pachyderm/pachyderm/src/server/pkg/cmdutil"
+	"github.com/spf13/cobra"
+)
+
+// Cmds returns the `pachctl bridge` commands for registration under the
+// root command.
+func Cmds(pc *client.APIClient) []*cobra.Command {
+	bridgeCmd := &cobra.Command{
+		Use:   "bridge",
+		Short: "Commands for mirroring PFS repos to/from external stores.",
+	}
+
+	var repo, branch, typ, endpoint, secretName string
+	configure := &cobra.Command{
+		Use:   "configure name",
+		Short: "Define a named, persistent sync between a PFS repo/branch and an external store.",
+		Run: cmdutil.RunFixedArgs(1, func(args []string) error {
+			return Save(pc, Config{
+				Name:       args[0],
+				Type:       typ,
+				Repo:       repo,
+				Branch:     branch,
+				Endpoint:   endpoint,
+				SecretName: secretName,
+			})
+		}),
+	}
+	configure.Flags().StringVar(&repo, "repo", "", "The PFS repo to sync.")
+	configure.Flags().StringVar(&branch, "branch", "master", "The PFS branch to sync.")
+	configure.Flags().StringVar(&typ, "type", "", "The bridge backend type. Built in: \"pachyderm\" (mirrors to/from another Pachyderm cluster). Additional backends (e.g. \"s3\", \"gcs\", \"git\") can be added via bridge.Register.")
+	configure.Flags().StringVar(&endpoint, "endpoint", "", "The external store's endpoint (bucket URL, git remote, cluster address, ...).")
+	configure.Flags().StringVar(&secretName, "credentials", "", "The name of the secret holding credentials for the endpoint.")
+	bridgeCmd.AddCommand(configure)
+
+	setSecret := &cobra.Command{
+		Use:   "set-secret name token",
+		Short: "Store a credential under name, for later reference from a bridge's --credentials.",
+		Run: cmdutil.RunFixedArgs(2, func(args []string) error {
+			return SaveSecret(pc, args[0], args[1])
+		}),
+	}
+	bridgeCmd.AddCommand(setSecret)
+
+	pull := &cobra.Command{
+		Use:   "pull name",
+		Short: "Ingest objects added to a bridge's external store since its last sync into a new commit.",
+		Run: cmdutil.RunFixedArgs(1, func(args []string) error {
+			return Pull(context.Background(), pc, args[0])
+		}),
+	}
+	bridgeCmd.AddCommand(pull)
+
+	push := &cobra.Command{
+		Use:   "push name",
+		Short: "Export commits made since a bridge's last sync to its external store.",
+		Run: cmdutil.RunFixedArgs(1, func(args []string) error {
+			return Push(context.Background(), pc, args[0])
+		}),
+	}
+	bridgeCmd.AddCommand(push)
+
+	ls := &cobra.Command{
+		Use:   "ls",
+		Short: "List configured bridges.",
+		Run: cmdutil.RunFixedArgs(0, func(args []string) error {
+			configs, err := List(pc)
+			if err != nil {
+				return err
+			}
+			w := tabwriter.NewWriter(os.Stdout, 0, 1, 2, ' ', 0)
+			fmt.Fprintln(w, "NAME\tTYPE\tREPO\tBRANCH\tENDPOINT\tCURSOR")
+			for _, config := range configs {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", config.Name, config.Type, config.Repo, config.Branch, config.Endpoint, config.Cursor)
+			}
+			return w.Flush()
+		}),
+	}
+	bridgeCmd.AddCommand(ls)
+
+	rm := &cobra.Command{
+		Use:   "rm name",
+		Short: "Remove a bridge's configuration.",
+		Run: cmdutil.RunFixedArgs(1, func(args []string) error {
+			return Remove(pc, args[0])
+		}),
+	}
+	bridgeCmd.AddCommand(rm)
+
+	return []*cobra.Command{bridgeCmd}
+}
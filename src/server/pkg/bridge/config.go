@@ -0,0 +1,126 @@
+package bridge
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pachyderm/pachyderm/src/client"
+)
+
+// configRepo is the system repo that holds one branch per configured
+// bridge, each with a single file (configFile) recording that bridge's
+// config and cursor, plus one branch per stored secret (see
+// secretBranchPrefix). Reusing PFS for bridge bookkeeping means bridges
+// survive a `pachctl` restart without a separate store to operate.
+const configRepo = "__bridges__"
+
+const configFile = "/config.json"
+
+// Config is a bridge's persistent configuration: the external endpoint it
+// syncs with, a reference to the credentials needed to reach it, and how
+// far it has synced so far.
+type Config struct {
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	Repo       string `json:"repo"`
+	Branch     string `json:"branch"`
+	Endpoint   string `json:"endpoint"`
+	SecretName string `json:"secretName"`
+	Cursor     Cursor `json:"cursor"`
+}
+
+// Save writes config to its branch in configRepo, creating the repo and
+// branch on first use.
+func Save(pc *client.APIClient, config Config) error {
+	if _, err := pc.InspectRepo(configRepo); err != nil {
+		if err := pc.CreateRepo(configRepo); err != nil {
+			return err
+		}
+	}
+	if _, err := pc.InspectBranch(configRepo, config.Name); err != nil {
+		if err := pc.CreateBranch(configRepo, config.Name, "", nil); err != nil {
+			return err
+		}
+	}
+
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = pc.PutFileOverwrite(configRepo, config.Name, configFile, bytes.NewReader(data), 0)
+	return err
+}
+
+// Load reads the named bridge's config back from configRepo.
+func Load(pc *client.APIClient, name string) (Config, error) {
+	var buf bytes.Buffer
+	if err := pc.GetFile(configRepo, name, configFile, 0, 0, &buf); err != nil {
+		return Config{}, fmt.Errorf("no such bridge \"%s\": %v", name, err)
+	}
+	var config Config
+	if err := json.Unmarshal(buf.Bytes(), &config); err != nil {
+		return Config{}, err
+	}
+	return config, nil
+}
+
+// List returns the configs of every bridge that's been configured.
+func List(pc *client.APIClient) ([]Config, error) {
+	repoInfo, err := pc.InspectRepo(configRepo)
+	if err != nil {
+		return nil, nil
+	}
+	var configs []Config
+	for _, branch := range repoInfo.Branches {
+		if strings.HasPrefix(branch.Name, secretBranchPrefix) {
+			continue
+		}
+		config, err := Load(pc, branch.Name)
+		if err != nil {
+			return nil, err
+		}
+		configs = append(configs, config)
+	}
+	return configs, nil
+}
+
+// Remove deletes a bridge's stored config. It does not touch the PFS repo
+// or external store the bridge was syncing.
+func Remove(pc *client.APIClient, name string) error {
+	return pc.DeleteBranch(configRepo, name, true)
+}
+
+// secretBranchPrefix namespaces a secret's branch in configRepo away from
+// the bridge-config branches Save/Load use, so a secret and a bridge can
+// share a name without colliding.
+const secretBranchPrefix = "secret-"
+
+// SaveSecret stores token in configRepo under name, for later lookup via
+// LoadSecret from a Config's SecretName. It's deliberately independent of
+// any one bridge, so the same secret can be referenced by several.
+func SaveSecret(pc *client.APIClient, name, token string) error {
+	branch := secretBranchPrefix + name
+	if _, err := pc.InspectRepo(configRepo); err != nil {
+		if err := pc.CreateRepo(configRepo); err != nil {
+			return err
+		}
+	}
+	if _, err := pc.InspectBranch(configRepo, branch); err != nil {
+		if err := pc.CreateBranch(configRepo, branch, "", nil); err != nil {
+			return err
+		}
+	}
+	_, err := pc.PutFileOverwrite(configRepo, branch, configFile, bytes.NewReader([]byte(token)), 0)
+	return err
+}
+
+// LoadSecret reads back a token stored with SaveSecret.
+func LoadSecret(pc *client.APIClient, name string) (string, error) {
+	var buf bytes.Buffer
+	if err := pc.GetFile(configRepo, secretBranchPrefix+name, configFile, 0, 0, &buf); err != nil {
+		return "", fmt.Errorf("no such secret \"%s\": %v", name, err)
+	}
+	return buf.String(), nil
+}
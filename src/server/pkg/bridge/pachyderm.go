@@ -0,0 +1,171 @@
+package bridge
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pachyderm/pachyderm/src/client"
+	"github.com/pachyderm/pachyderm/src/client/pfs"
+)
+
+func init() {
+	Register("pachyderm", newPachydermBridge)
+}
+
+// pachydermBridge mirrors a PFS repo/branch to or from another Pachyderm
+// cluster. Its Cursor is the source cluster's branch HEAD commit ID as of
+// the last sync (the remote's after a Pull, the local one's after a
+// Push), so Pull/Push are no-ops once the source has no new commit.
+// It's also the reference implementation of the Bridge interface — an
+// S3/GCS/Git backend would follow the same shape, just swapping the
+// remote *client.APIClient for that store's own SDK.
+type pachydermBridge struct {
+	local  *client.APIClient
+	remote *client.APIClient
+	config Config
+}
+
+func newPachydermBridge(pc *client.APIClient, config Config) (Bridge, error) {
+	remote, err := client.NewFromAddress(config.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to remote cluster %q: %v", config.Endpoint, err)
+	}
+	if config.SecretName != "" {
+		token, err := LoadSecret(pc, config.SecretName)
+		if err != nil {
+			return nil, fmt.Errorf("loading credentials %q for remote cluster %q: %v", config.SecretName, config.Endpoint, err)
+		}
+		remote = remote.AuthorizeAs(token)
+	}
+	return &pachydermBridge{local: pc, remote: remote, config: config}, nil
+}
+
+func (b *pachydermBridge) Pull(ctx context.Context, since Cursor) (Cursor, error) {
+	return mirror(b.remote, b.local, b.config.Repo, b.config.Branch, since)
+}
+
+func (b *pachydermBridge) Push(ctx context.Context, since Cursor) (Cursor, error) {
+	return mirror(b.local, b.remote, b.config.Repo, b.config.Branch, since)
+}
+
+// mirror copies every file src added or changed, and deletes every file
+// src removed, between since and src's repo@branch HEAD commit, into a
+// new commit on the same repo@branch in dst — so a sync only ever moves
+// what's actually changed, per the package doc, rather than re-copying
+// src's whole tree every time. It returns src's HEAD commit ID to resume
+// from on the next sync.
+func mirror(src, dst *client.APIClient, repo, branch string, since Cursor) (Cursor, error) {
+	branchInfo, err := src.InspectBranch(repo, branch)
+	if err != nil {
+		return since, err
+	}
+	if branchInfo.Head == nil {
+		return since, nil
+	}
+	head := branchInfo.Head.ID
+	if Cursor(head) == since {
+		return since, nil
+	}
+
+	present, err := filesByPath(src, repo, head)
+	if err != nil {
+		return since, err
+	}
+
+	// since == "" means this is the first sync: there's nothing to diff
+	// against, so every file in present is an add and nothing is removed.
+	var prior map[string]*pfs.FileInfo
+	if since != "" {
+		prior, err = filesByPath(src, repo, string(since))
+		if err != nil {
+			return since, err
+		}
+	}
+
+	var changed, removed []string
+	for path, fi := range present {
+		if old, ok := prior[path]; !ok || old.Hash != fi.Hash {
+			changed = append(changed, path)
+		}
+	}
+	for path := range prior {
+		if _, ok := present[path]; !ok {
+			removed = append(removed, path)
+		}
+	}
+	if len(changed) == 0 && len(removed) == 0 {
+		return Cursor(head), nil
+	}
+
+	if _, err := dst.InspectRepo(repo); err != nil {
+		if err := dst.CreateRepo(repo); err != nil {
+			return since, err
+		}
+	}
+
+	commit, err := dst.StartCommit(repo, branch)
+	if err != nil {
+		return since, err
+	}
+
+	for _, path := range changed {
+		reader, err := src.GetFileReader(repo, head, path, 0, 0)
+		if err != nil {
+			dst.DeleteCommit(repo, commit.ID)
+			return since, err
+		}
+		if _, err := dst.PutFileOverwrite(repo, commit.ID, path, reader, 0); err != nil {
+			dst.DeleteCommit(repo, commit.ID)
+			return since, err
+		}
+	}
+	for _, path := range removed {
+		if err := dst.DeleteFile(repo, commit.ID, path); err != nil {
+			dst.DeleteCommit(repo, commit.ID)
+			return since, err
+		}
+	}
+
+	if err := dst.FinishCommit(repo, commit.ID); err != nil {
+		return since, err
+	}
+	return Cursor(head), nil
+}
+
+// filesByPath walks every file (not directory) in the given commit and
+// returns it keyed by path, so mirror can diff one commit's file set
+// against another's.
+func filesByPath(pc *client.APIClient, repo, commitID string) (map[string]*pfs.FileInfo, error) {
+	fileInfos, err := walkFiles(pc, repo, commitID, "/")
+	if err != nil {
+		return nil, err
+	}
+	present := make(map[string]*pfs.FileInfo, len(fileInfos))
+	for _, fi := range fileInfos {
+		present[fi.File.Path] = fi
+	}
+	return present, nil
+}
+
+// walkFiles recursively lists every file (not directory) under path in
+// the given commit, since ListFile only returns path's direct children.
+func walkFiles(pc *client.APIClient, repo, commitID, path string) ([]*pfs.FileInfo, error) {
+	children, err := pc.ListFile(repo, commitID, path)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*pfs.FileInfo
+	for _, fi := range children {
+		if fi.FileType == pfs.FileType_DIR {
+			nested, err := walkFiles(pc, repo, commitID, fi.File.Path)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, nested...)
+			continue
+		}
+		result = append(result, fi)
+	}
+	return result, nil
+}
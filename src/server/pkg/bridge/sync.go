@@ -0,0 +1,47 @@
+package bridge
+
+import (
+	"context"
+
+	"github.com/pachyderm/pachyderm/src/client"
+)
+
+// Pull loads the named bridge's config, runs one Pull against its
+// backend, and persists the returned cursor so the next Pull only fetches
+// what's new.
+func Pull(ctx context.Context, pc *client.APIClient, name string) error {
+	config, err := Load(pc, name)
+	if err != nil {
+		return err
+	}
+	b, err := New(pc, config)
+	if err != nil {
+		return err
+	}
+	cursor, err := b.Pull(ctx, config.Cursor)
+	if err != nil {
+		return err
+	}
+	config.Cursor = cursor
+	return Save(pc, config)
+}
+
+// Push loads the named bridge's config, runs one Push against its
+// backend, and persists the returned cursor so the next Push only sends
+// what's new.
+func Push(ctx context.Context, pc *client.APIClient, name string) error {
+	config, err := Load(pc, name)
+	if err != nil {
+		return err
+	}
+	b, err := New(pc, config)
+	if err != nil {
+		return err
+	}
+	cursor, err := b.Push(ctx, config.Cursor)
+	if err != nil {
+		return err
+	}
+	config.Cursor = cursor
+	return Save(pc, config)
+}
@@ -123,11 +123,25 @@ func (r *RepeatedStringArg) Type() string {
 	return "[]string"
 }
 
+// docsSubcommands records, for each docs-style command set up via
+// SetDocsUsage, the related subcommands it renders inline. GenerateDocs
+// consults this to reattach those subcommands for the duration of doc
+// generation, since they otherwise only exist transiently inside the
+// UsageFunc closure below.
+var docsSubcommands = map[*cobra.Command][]*cobra.Command{}
+
+// DocsSubcommands returns the related subcommands registered for a
+// docs-style command via SetDocsUsage, or nil if command isn't one.
+func DocsSubcommands(command *cobra.Command) []*cobra.Command {
+	return docsSubcommands[command]
+}
+
 // SetDocsUsage sets the usage string for a docs-style command.  Docs commands
 // have no functionality except to output some docs and related commands, and
 // should not specify a 'Run' attribute.
 func SetDocsUsage(command *cobra.Command, subcommands []*cobra.Command) {
-    command.SetUsageTemplate(`Usage:
+	docsSubcommands[command] = subcommands
+	command.SetUsageTemplate(`Usage:
   pachctl [command]{{if gt .Aliases 0}}
 
 Aliases:
@@ -147,24 +161,24 @@ Additional help topics:{{range .Commands}}{{if .IsHelpCommand}}
   {{rpad .CommandPath .CommandPathPadding}} {{.Short}}{{end}}{{end}}{{end}}
 `)
 
-    command.SetHelpTemplate(`{{or .Long .Short}}
+	command.SetHelpTemplate(`{{or .Long .Short}}
 {{.UsageString}}`)
 
-    // This song-and-dance is so that we can render the related commands without
-    // actually having them usable as subcommands of the docs command.
-    // That is, we don't want `pachctl job list-job` to work, it should just
-    // be `pachctl list-job`.  Therefore, we lazily add/remove the subcommands
-    // only when we try to render usage for the docs command.
-    originalUsage := command.UsageFunc()
-    command.SetUsageFunc(func (c *cobra.Command) error {
-        newUsage := command.UsageFunc()
-        command.SetUsageFunc(originalUsage)
-        defer command.SetUsageFunc(newUsage)
-
-        command.AddCommand(subcommands...)
-        defer command.RemoveCommand(subcommands...)
-
-        command.Usage()
-        return nil
-    })
+	// This song-and-dance is so that we can render the related commands without
+	// actually having them usable as subcommands of the docs command.
+	// That is, we don't want `pachctl job list-job` to work, it should just
+	// be `pachctl list-job`.  Therefore, we lazily add/remove the subcommands
+	// only when we try to render usage for the docs command.
+	originalUsage := command.UsageFunc()
+	command.SetUsageFunc(func(c *cobra.Command) error {
+		newUsage := command.UsageFunc()
+		command.SetUsageFunc(originalUsage)
+		defer command.SetUsageFunc(newUsage)
+
+		command.AddCommand(subcommands...)
+		defer command.RemoveCommand(subcommands...)
+
+		command.Usage()
+		return nil
+	})
 }
@@ -0,0 +1,215 @@
+package cmdutil
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pachyderm/pachyderm/src/client"
+	"github.com/spf13/cobra"
+)
+
+// completionCache memoizes the results of cluster-backed completions for
+// the lifetime of a single `pachctl ... <TAB>` invocation, so that
+// completing a command with several Pachyderm-aware flags doesn't make a
+// redundant API call per flag.
+type completionCache struct {
+	repos     []string
+	branches  map[string][]string
+	commits   map[string][]string
+	pipelines []string
+	jobs      []string
+}
+
+// RegisterCompletions installs `ValidArgsFunction`s on every command in
+// root's tree whose positional arguments name a repo, a `repo@branch`, a
+// `repo@commit`, a pipeline, or a job, so that `pachctl get-file <TAB>`
+// can complete against the live cluster. If pc is nil, or the cluster is
+// unreachable when a completion is requested, completions degrade to "no
+// suggestions" rather than blocking or erroring, since a broken shell
+// completion is worse than a missing one.
+func RegisterCompletions(root *cobra.Command, pc *client.APIClient) {
+	cache := &completionCache{branches: map[string][]string{}, commits: map[string][]string{}}
+
+	var visit func(cmd *cobra.Command)
+	visit = func(cmd *cobra.Command) {
+		if cmd.ValidArgsFunction == nil {
+			if kind, ok := argCompletionKind(cmd); ok {
+				cmd.ValidArgsFunction = completionFuncFor(pc, cache, kind)
+			}
+		}
+		for _, child := range cmd.Commands() {
+			visit(child)
+		}
+	}
+	visit(root)
+}
+
+// completionKind identifies what shape of Pachyderm-aware value a
+// command's positional arguments take.
+type completionKind int
+
+const (
+	completionKindNone completionKind = iota
+	completionKindRepo
+	completionKindBranch
+	completionKindCommit
+	completionKindPipeline
+	completionKindJob
+)
+
+// argCompletionKind looks at a command's annotations to decide what kind
+// of completion it should use. Commands opt in by setting the
+// "pachctl.complete" annotation, mirroring the argument shapes already
+// parsed by ParseCommits/ParseBranches.
+func argCompletionKind(cmd *cobra.Command) (completionKind, bool) {
+	kind, ok := cmd.Annotations["pachctl.complete"]
+	if !ok {
+		return completionKindNone, false
+	}
+	switch kind {
+	case "repo":
+		return completionKindRepo, true
+	case "branch":
+		return completionKindBranch, true
+	case "commit":
+		return completionKindCommit, true
+	case "pipeline":
+		return completionKindPipeline, true
+	case "job":
+		return completionKindJob, true
+	default:
+		return completionKindNone, false
+	}
+}
+
+func completionFuncFor(pc *client.APIClient, cache *completionCache, kind completionKind) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if pc == nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		var candidates []string
+		switch kind {
+		case completionKindRepo:
+			candidates = completeRepos(pc, cache)
+		case completionKindBranch:
+			candidates = completeBranches(pc, cache, toComplete)
+		case completionKindCommit:
+			candidates = completeBranches(pc, cache, toComplete)
+		case completionKindPipeline:
+			candidates = completePipelines(pc, cache)
+		case completionKindJob:
+			candidates = completeJobs(pc, cache)
+		}
+
+		var matches []string
+		for _, c := range candidates {
+			if strings.HasPrefix(c, toComplete) {
+				matches = append(matches, c)
+			}
+		}
+		return matches, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+func completeRepos(pc *client.APIClient, cache *completionCache) []string {
+	if cache.repos != nil {
+		return cache.repos
+	}
+	repos, err := pc.ListRepo()
+	if err != nil {
+		return nil
+	}
+	for _, repo := range repos {
+		cache.repos = append(cache.repos, repo.Repo.Name)
+	}
+	return cache.repos
+}
+
+// completeBranches completes "repo@branch" or "repo@commit" arguments. If
+// toComplete doesn't yet contain an "@", only the repo names are offered;
+// otherwise the repo's branches are offered as the suffix.
+func completeBranches(pc *client.APIClient, cache *completionCache, toComplete string) []string {
+	parts := strings.SplitN(toComplete, "@", 2)
+	if len(parts) == 1 {
+		return completeRepos(pc, cache)
+	}
+
+	repo := parts[0]
+	if branches, ok := cache.branches[repo]; ok {
+		return prefixed(repo, branches)
+	}
+
+	repoInfo, err := pc.InspectRepo(repo)
+	if err != nil {
+		return nil
+	}
+	var branches []string
+	for _, branch := range repoInfo.Branches {
+		branches = append(branches, branch.Name)
+	}
+	cache.branches[repo] = branches
+	return prefixed(repo, branches)
+}
+
+func prefixed(repo string, branches []string) []string {
+	var result []string
+	for _, branch := range branches {
+		result = append(result, repo+"@"+branch)
+	}
+	return result
+}
+
+func completePipelines(pc *client.APIClient, cache *completionCache) []string {
+	if cache.pipelines != nil {
+		return cache.pipelines
+	}
+	pipelineInfos, err := pc.ListPipeline()
+	if err != nil {
+		return nil
+	}
+	for _, pipelineInfo := range pipelineInfos {
+		cache.pipelines = append(cache.pipelines, pipelineInfo.Pipeline.Name)
+	}
+	return cache.pipelines
+}
+
+// NewCompletionCommand returns the `pachctl completion` command, which
+// emits a cobra-generated completion script for the requested shell.
+// RegisterCompletions should be called on root before this command runs
+// so the emitted script includes the Pachyderm-aware completers above.
+func NewCompletionCommand(root *cobra.Command) *cobra.Command {
+	return &cobra.Command{
+		Use:       "completion [bash|zsh|fish]",
+		Short:     "Print a shell completion script for pachctl.",
+		Long:      "Print a shell completion script for pachctl. Source the output, or write it to your shell's completion directory, to enable tab-completion of Pachyderm repos, branches, commits, pipelines, and jobs.",
+		ValidArgs: []string{"bash", "zsh", "fish"},
+		Run: RunFixedArgs(1, func(args []string) error {
+			switch args[0] {
+			case "bash":
+				return root.GenBashCompletion(os.Stdout)
+			case "zsh":
+				return root.GenZshCompletion(os.Stdout)
+			case "fish":
+				return root.GenFishCompletion(os.Stdout, true)
+			default:
+				return fmt.Errorf("unrecognized shell \"%s\", must be one of bash, zsh, fish", args[0])
+			}
+		}),
+	}
+}
+
+func completeJobs(pc *client.APIClient, cache *completionCache) []string {
+	if cache.jobs != nil {
+		return cache.jobs
+	}
+	jobInfos, err := pc.ListJob("", nil, nil)
+	if err != nil {
+		return nil
+	}
+	for _, jobInfo := range jobInfos {
+		cache.jobs = append(cache.jobs, jobInfo.Job.ID)
+	}
+	return cache.jobs
+}
@@ -0,0 +1,167 @@
+package cmdutil
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/cobra/doc"
+)
+
+// DocFormat is a documentation output format supported by GenerateDocs.
+type DocFormat string
+
+const (
+	// DocFormatMan generates man(1) pages, one per command.
+	DocFormatMan DocFormat = "man"
+	// DocFormatMarkdown generates Markdown pages, one per command.
+	DocFormatMarkdown DocFormat = "markdown"
+	// DocFormatReST generates reStructuredText pages, one per command.
+	DocFormatReST DocFormat = "rst"
+)
+
+// manifestName is the name of the file GenerateDocs writes alongside the
+// generated pages, listing every file it produced. Packaging (deb/rpm/brew)
+// reads this to know what to install, rather than globbing outDir.
+const manifestName = "manifest.txt"
+
+// GenerateDocs writes documentation for root and every subcommand in its
+// tree to outDir, in the given format, and writes a manifest listing the
+// generated files. cobra/doc's generators walk cmd.Commands() directly,
+// which is empty for a SetDocsUsage command outside of rendering its own
+// usage string, so GenerateDocs temporarily reattaches each docs
+// command's related subcommands for the duration of generation — giving
+// every docs page its related commands inline instead of showing none.
+func GenerateDocs(root *cobra.Command, outDir string, format DocFormat) error {
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return err
+	}
+
+	restore := attachDocsSubcommands(root)
+	defer restore()
+
+	// cobra/doc (re)creates every page it writes, so any page in outDir
+	// modified since start was produced by this run, even one that a
+	// previous run had already written.
+	start := time.Now().Truncate(time.Second)
+
+	var err error
+	switch format {
+	case DocFormatMan:
+		err = doc.GenManTree(root, &doc.GenManHeader{
+			Title:   "PACHCTL",
+			Section: "1",
+			Source:  "Pachyderm",
+		}, outDir)
+	case DocFormatMarkdown:
+		err = doc.GenMarkdownTree(root, outDir)
+	case DocFormatReST:
+		err = doc.GenReSTTree(root, outDir)
+	default:
+		return fmt.Errorf("unrecognized doc format \"%s\", must be one of %s, %s, %s", format, DocFormatMan, DocFormatMarkdown, DocFormatReST)
+	}
+	if err != nil {
+		return err
+	}
+
+	after, err := filepath.Glob(filepath.Join(outDir, "*"))
+	if err != nil {
+		return err
+	}
+	var generated []string
+	for _, path := range after {
+		if filepath.Base(path) == manifestName {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		if !info.ModTime().Before(start) {
+			generated = append(generated, filepath.Base(path))
+		}
+	}
+
+	manifest, err := os.Create(filepath.Join(outDir, manifestName))
+	if err != nil {
+		return err
+	}
+	defer manifest.Close()
+	for _, name := range generated {
+		if _, err := fmt.Fprintln(manifest, name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// relocatedSubcommand remembers where a subcommand actually lives in the
+// tree, so it can be moved back there (rather than merely detached) once
+// doc generation is done.
+type relocatedSubcommand struct {
+	cmd            *cobra.Command
+	originalParent *cobra.Command
+}
+
+// attachDocsSubcommands walks root's tree, and for every docs-style
+// command it finds (per DocsSubcommands), attaches its related
+// subcommands so they're visible to cobra/doc's generators. It returns a
+// func that moves them back, restoring the tree to how pachctl actually
+// runs it (where those commands live directly under their original
+// parent, not nested under the docs command). cobra's RemoveCommand only
+// clears a command's parent pointer — it doesn't know what the parent
+// used to be — so we snapshot each subcommand's real parent up front and
+// reattach to it explicitly rather than leaving the command parentless.
+func attachDocsSubcommands(root *cobra.Command) func() {
+	var docsCommands []*cobra.Command
+	var relocated []relocatedSubcommand
+	var visit func(cmd *cobra.Command)
+	visit = func(cmd *cobra.Command) {
+		if subcommands := DocsSubcommands(cmd); len(subcommands) > 0 {
+			docsCommands = append(docsCommands, cmd)
+			for _, sub := range subcommands {
+				if parent := sub.Parent(); parent != nil {
+					relocated = append(relocated, relocatedSubcommand{cmd: sub, originalParent: parent})
+					parent.RemoveCommand(sub)
+				}
+			}
+			cmd.AddCommand(subcommands...)
+		}
+		for _, child := range cmd.Commands() {
+			visit(child)
+		}
+	}
+	visit(root)
+
+	return func() {
+		for _, cmd := range docsCommands {
+			cmd.RemoveCommand(DocsSubcommands(cmd)...)
+		}
+		for _, r := range relocated {
+			r.originalParent.AddCommand(r.cmd)
+		}
+	}
+}
+
+// NewGenDocsCommand returns the hidden `pachctl gen-docs` command that
+// drives GenerateDocs. It's hidden because it's a packaging-time tool,
+// not something an end user runs.
+func NewGenDocsCommand(root *cobra.Command) *cobra.Command {
+	var outDir string
+	var format string
+
+	cmd := &cobra.Command{
+		Use:    "gen-docs",
+		Short:  "Generate man pages and Markdown/reST reference docs for pachctl.",
+		Hidden: true,
+		Run: RunFixedArgs(0, func(args []string) error {
+			return GenerateDocs(root, outDir, DocFormat(format))
+		}),
+	}
+	cmd.Flags().StringVar(&outDir, "out", "docs", "The directory to write generated docs to.")
+	cmd.Flags().StringVar(&format, "format", string(DocFormatMarkdown), fmt.Sprintf("The doc format to generate: one of %s, %s, %s.", DocFormatMan, DocFormatMarkdown, DocFormatReST))
+	return cmd
+}
@@ -0,0 +1,21 @@
+package tui
+
+import (
+	"github.com/pachyderm/pachyderm/src/client"
+	"github.com/pachyderm/pachyderm/src/server/pkg/cmdutil"
+	"github.com/spf13/cobra"
+)
+
+// Cmds returns the `pachctl tui` command for registration under the root
+// command.
+func Cmds(pc *client.APIClient) []*cobra.Command {
+	tuiCmd := &cobra.Command{
+		Use:   "tui",
+		Short: "Launch an interactive terminal UI for browsing and operating on the cluster.",
+		Long:  "Launch a full-screen terminal UI with repos/branches on the left, commits in the middle, and a file tree + preview on the right. Press 'd' to diff the selected commit against its parent, 'l' to tail the selected pipeline's job logs, 'p'/'x' to put/delete a file, and 'q' to quit.",
+		Run: cmdutil.RunFixedArgs(0, func(args []string) error {
+			return New(pc).Run()
+		}),
+	}
+	return []*cobra.Command{tuiCmd}
+}
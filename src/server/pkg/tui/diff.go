@@ -0,0 +1,38 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pachyderm/pachyderm/src/client/pfs"
+)
+
+// formatDiff renders a unified-style summary of which paths were added,
+// removed, or changed between two ListFile results.
+func formatDiff(oldFiles, newFiles []*pfs.FileInfo) string {
+	oldByPath := map[string]*pfs.FileInfo{}
+	for _, fi := range oldFiles {
+		oldByPath[fi.File.Path] = fi
+	}
+	newByPath := map[string]*pfs.FileInfo{}
+	for _, fi := range newFiles {
+		newByPath[fi.File.Path] = fi
+	}
+
+	var b strings.Builder
+	for path, fi := range newByPath {
+		old, existed := oldByPath[path]
+		switch {
+		case !existed:
+			fmt.Fprintf(&b, "+ %s (%d bytes)\n", path, fi.SizeBytes)
+		case old.Hash != fi.Hash:
+			fmt.Fprintf(&b, "~ %s (%d -> %d bytes)\n", path, old.SizeBytes, fi.SizeBytes)
+		}
+	}
+	for path := range oldByPath {
+		if _, stillExists := newByPath[path]; !stillExists {
+			fmt.Fprintf(&b, "- %s\n", path)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,119 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/rivo/tview"
+)
+
+// promptPutFile shows a small form collecting a local path and a
+// destination path, then calls putFile with the answers.
+func (a *App) promptPutFile() {
+	form := tview.NewForm()
+	form.AddInputField("Local path", "", 40, nil, nil)
+	form.AddInputField("Destination path", "", 40, nil, nil)
+	form.AddButton("Upload", func() {
+		local := form.GetFormItem(0).(*tview.InputField).GetText()
+		dest := form.GetFormItem(1).(*tview.InputField).GetText()
+		a.app.SetRoot(a.rootFlex(), true)
+		if err := a.putFile(local, dest); err != nil {
+			a.preview.SetText(fmt.Sprintf("error uploading: %v", err))
+		}
+	})
+	form.AddButton("Cancel", func() {
+		a.app.SetRoot(a.rootFlex(), true)
+	})
+	form.SetBorder(true).SetTitle("Put File")
+	a.app.SetRoot(form, true)
+}
+
+// promptDeleteFile shows a small form collecting a path to delete, then
+// calls deleteFile with the answer.
+func (a *App) promptDeleteFile() {
+	form := tview.NewForm()
+	form.AddInputField("Path to delete", "", 40, nil, nil)
+	form.AddButton("Delete", func() {
+		path := form.GetFormItem(0).(*tview.InputField).GetText()
+		a.app.SetRoot(a.rootFlex(), true)
+		if err := a.deleteFile(path); err != nil {
+			a.preview.SetText(fmt.Sprintf("error deleting: %v", err))
+		}
+	})
+	form.AddButton("Cancel", func() {
+		a.app.SetRoot(a.rootFlex(), true)
+	})
+	form.SetBorder(true).SetTitle("Delete File")
+	a.app.SetRoot(form, true)
+}
+
+// putFile uploads localPath to the currently-selected repo/branch at the
+// given destination path within a single commit, then refreshes the
+// commit list so the new commit shows up immediately.
+func (a *App) putFile(localPath, destPath string) error {
+	if a.selectedRepo == "" || a.selectedBranch == "" {
+		return fmt.Errorf("no repo/branch selected")
+	}
+	f, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := a.pc.PutFileOverwrite(a.selectedRepo, a.selectedBranch, destPath, f, 0); err != nil {
+		return err
+	}
+	a.onSelectRepoBranch(fmt.Sprintf("%s@%s", a.selectedRepo, a.selectedBranch))
+	return nil
+}
+
+// deleteFile removes path from the currently-selected repo/branch within
+// a single commit, then refreshes the commit list.
+func (a *App) deleteFile(path string) error {
+	if a.selectedRepo == "" || a.selectedBranch == "" {
+		return fmt.Errorf("no repo/branch selected")
+	}
+	if err := a.pc.DeleteFile(a.selectedRepo, a.selectedBranch, path); err != nil {
+		return err
+	}
+	a.onSelectRepoBranch(fmt.Sprintf("%s@%s", a.selectedRepo, a.selectedBranch))
+	return nil
+}
+
+// tailJobLogs streams the logs of repoOrPipeline's most recent job into
+// the preview pane as they arrive, if it names a pipeline. GetLogs
+// follows indefinitely, so this runs on its own goroutine and pushes
+// each update back to the main loop via QueueUpdateDraw — calling
+// iter.Next()/Draw() directly from the key-handling goroutine would
+// block the whole UI, including the 'q' keypress needed to quit, for as
+// long as the tail runs. The goroutine is bound to a.ctx, which Run
+// cancels on quit, so a tail started before 'q' stops streaming rather
+// than continuing to run after the TUI has exited.
+func (a *App) tailJobLogs(repoOrPipeline string) {
+	if _, err := a.pc.InspectPipeline(repoOrPipeline); err != nil {
+		a.preview.Clear()
+		fmt.Fprintf(a.preview, "%q is not a pipeline, no logs to tail\n", repoOrPipeline)
+		return
+	}
+
+	a.preview.Clear()
+
+	ctx := a.ctx
+	go func() {
+		iter := a.pc.WithCtx(ctx).GetLogs(repoOrPipeline, "", nil, "", false, true, 0)
+		for iter.Next() {
+			if ctx.Err() != nil {
+				return
+			}
+			message := iter.Message().Message
+			a.app.QueueUpdateDraw(func() {
+				fmt.Fprintln(a.preview, message)
+			})
+		}
+		if err := iter.Err(); err != nil && ctx.Err() == nil {
+			a.app.QueueUpdateDraw(func() {
+				fmt.Fprintf(a.preview, "error tailing logs: %v\n", err)
+			})
+		}
+	}()
+}
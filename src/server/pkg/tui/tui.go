@@ -0,0 +1,198 @@
+// Package tui implements a full-screen terminal UI for browsing and
+// operating on a Pachyderm cluster, as an alternative to stringing
+// together many `pachctl list-*`/`inspect-*` invocations.
+package tui
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/pachyderm/pachyderm/src/client"
+	"github.com/rivo/tview"
+)
+
+// App is the running TUI: a three-pane layout of repos/branches, commits,
+// and a file tree + preview, backed by a single Pachyderm client.
+type App struct {
+	pc  *client.APIClient
+	app *tview.Application
+
+	repos   *tview.List
+	commits *tview.List
+	files   *tview.TreeView
+	preview *tview.TextView
+
+	selectedRepo   string
+	selectedBranch string
+
+	// ctx is canceled when the TUI quits, so a log tail started with 'l'
+	// (see tailJobLogs) stops streaming instead of continuing to run in
+	// the background after the terminal's been handed back.
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// New builds an App wired to pc. Call Run to take over the terminal.
+func New(pc *client.APIClient) *App {
+	ctx, cancel := context.WithCancel(context.Background())
+	a := &App{
+		pc:      pc,
+		app:     tview.NewApplication(),
+		repos:   tview.NewList().ShowSecondaryText(false),
+		commits: tview.NewList().ShowSecondaryText(true),
+		files:   tview.NewTreeView(),
+		preview: tview.NewTextView(),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+	a.repos.SetBorder(true).SetTitle("Repos / Branches")
+	a.commits.SetBorder(true).SetTitle("Commits")
+	a.files.SetBorder(true).SetTitle("Files")
+	a.preview.SetBorder(true).SetTitle("Preview")
+
+	a.repos.SetChangedFunc(func(i int, repoBranch string, _ string, _ rune) {
+		a.onSelectRepoBranch(repoBranch)
+	})
+	a.commits.SetChangedFunc(func(i int, commitID string, _ string, _ rune) {
+		a.onSelectCommit(commitID)
+	})
+
+	return a
+}
+
+// Run loads the initial repo list and takes over the terminal until the
+// user quits (q) or an unrecoverable error occurs.
+func (a *App) Run() error {
+	defer a.cancel()
+
+	if err := a.reloadRepos(); err != nil {
+		return err
+	}
+
+	a.app.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Rune() {
+		case 'q':
+			a.cancel()
+			a.app.Stop()
+			return nil
+		case 'd':
+			a.diffSelectedCommits()
+			return nil
+		case 'l':
+			if a.selectedRepo != "" {
+				a.tailJobLogs(a.selectedRepo)
+			}
+			return nil
+		case 'p':
+			a.promptPutFile()
+			return nil
+		case 'x':
+			a.promptDeleteFile()
+			return nil
+		}
+		return event
+	})
+
+	return a.app.SetRoot(a.rootFlex(), true).Run()
+}
+
+// rootFlex rebuilds the three-pane layout. It's called both at startup
+// and whenever a modal form (put-file, delete-file) needs to hand focus
+// back to the main view.
+func (a *App) rootFlex() tview.Primitive {
+	filesAndPreview := tview.NewFlex().
+		AddItem(a.files, 0, 1, false).
+		AddItem(a.preview, 0, 1, false)
+	return tview.NewFlex().
+		AddItem(a.repos, 0, 1, true).
+		AddItem(a.commits, 0, 1, false).
+		AddItem(filesAndPreview, 0, 2, false)
+}
+
+func (a *App) reloadRepos() error {
+	repoInfos, err := a.pc.ListRepo()
+	if err != nil {
+		return err
+	}
+	a.repos.Clear()
+	for _, repoInfo := range repoInfos {
+		for _, branch := range repoInfo.Branches {
+			label := fmt.Sprintf("%s@%s", repoInfo.Repo.Name, branch.Name)
+			a.repos.AddItem(label, "", 0, nil)
+		}
+	}
+	return nil
+}
+
+func (a *App) onSelectRepoBranch(repoBranch string) {
+	repo, branch, ok := splitRepoBranch(repoBranch)
+	if !ok {
+		return
+	}
+	a.selectedRepo, a.selectedBranch = repo, branch
+
+	commitInfos, err := a.pc.ListCommit(repo, branch, "", uint64(0))
+	if err != nil {
+		a.preview.SetText(fmt.Sprintf("error listing commits: %v", err))
+		return
+	}
+	a.commits.Clear()
+	for _, commitInfo := range commitInfos {
+		a.commits.AddItem(commitInfo.Commit.ID, "", 0, nil)
+	}
+}
+
+func (a *App) onSelectCommit(commitID string) {
+	if a.selectedRepo == "" {
+		return
+	}
+	root := tview.NewTreeNode("/")
+	a.files.SetRoot(root).SetCurrentNode(root)
+
+	fileInfos, err := a.pc.ListFile(a.selectedRepo, commitID, "/")
+	if err != nil {
+		a.preview.SetText(fmt.Sprintf("error listing files: %v", err))
+		return
+	}
+	for _, fi := range fileInfos {
+		node := tview.NewTreeNode(fi.File.Path)
+		node.SetReference(commitID)
+		root.AddChild(node)
+	}
+}
+
+// diffSelectedCommits shows, in the preview pane, the file-level diff
+// between the selected commit and the commit listed below it.
+func (a *App) diffSelectedCommits() {
+	idx := a.commits.GetCurrentItem()
+	if idx < 0 || idx+1 >= a.commits.GetItemCount() {
+		return
+	}
+	// commits are listed newest-first (see onSelectRepoBranch), so the
+	// item below the current selection is the older commit.
+	newCommit, _ := a.commits.GetItemText(idx)
+	oldCommit, _ := a.commits.GetItemText(idx + 1)
+
+	newFiles, err := a.pc.ListFile(a.selectedRepo, newCommit, "/")
+	if err != nil {
+		a.preview.SetText(fmt.Sprintf("error diffing: %v", err))
+		return
+	}
+	oldFiles, err := a.pc.ListFile(a.selectedRepo, oldCommit, "/")
+	if err != nil {
+		a.preview.SetText(fmt.Sprintf("error diffing: %v", err))
+		return
+	}
+
+	a.preview.SetText(formatDiff(oldFiles, newFiles))
+}
+
+func splitRepoBranch(s string) (repo, branch string, ok bool) {
+	for i := len(s) - 1; i >= 0; i-- {
+		if s[i] == '@' {
+			return s[:i], s[i+1:], true
+		}
+	}
+	return "", "", false
+}